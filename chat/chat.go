@@ -0,0 +1,35 @@
+// Package chat はSlack/Discord/Mattermostなど異なるチャットサービスを抽象化するための
+// Backendインターフェースを提供します。
+package chat
+
+// EventType はBackendから届くイベントの種類です。
+type EventType string
+
+const (
+	// EventTypeMessage は通常のテキストメッセージを表します。
+	EventTypeMessage EventType = "message"
+	// EventTypeMention はボット宛てのメンションを表します。
+	EventTypeMention EventType = "mention"
+)
+
+// Event はBackend非依存の受信イベントを表します。
+type Event struct {
+	Type      EventType
+	ChannelID string
+	UserID    string
+	Text      string
+}
+
+// Backend は借りる/返す/状態確認といったコアロジックが依存するチャットサービスの
+// 最小限の操作を表すインターフェースです。Slack固有のBlock Kitやモーダルなど、
+// この抽象に収まらない機能は各実装固有の追加メソッドとして提供されます。
+type Backend interface {
+	// SendMessage はchannelIDにtextを投稿します。
+	SendMessage(channelID, text string) error
+	// LookupUser はuserIDの表示名を返します。
+	LookupUser(userID string) (string, error)
+	// Events はBackendが受信したイベントを読み出すチャンネルを返します。
+	Events() <-chan Event
+	// Close はBackendが保持するコネクションを閉じます。
+	Close() error
+}
@@ -0,0 +1,105 @@
+// Package discordchat はdiscordgoをラップした chat.Backend 実装を提供します。
+package discordchat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"hihumikan/master-card-manager/chat"
+	"hihumikan/master-card-manager/metrics"
+)
+
+const backendName = "discord"
+
+// Backend は chat.Backend の Discord実装です。
+type Backend struct {
+	session *discordgo.Session
+	events  chan chat.Event
+}
+
+// New はBotトークン(Bot接頭辞なし)からBackendを作成し、Discordゲートウェイへ接続します。
+func New(token string) (*Backend, error) {
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, fmt.Errorf("Discordセッションの作成に失敗しました: %v", err)
+	}
+	session.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentsMessageContent
+
+	b := &Backend{
+		session: session,
+		events:  make(chan chat.Event, 64),
+	}
+	session.AddHandler(b.onMessageCreate)
+	session.AddHandler(b.onResumed)
+
+	if err := session.Open(); err != nil {
+		return nil, fmt.Errorf("Discordゲートウェイへの接続に失敗しました: %v", err)
+	}
+	metrics.ReconnectsTotal.WithLabelValues(backendName).Inc()
+	return b, nil
+}
+
+func (b *Backend) onResumed(s *discordgo.Session, r *discordgo.Resumed) {
+	metrics.ReconnectsTotal.WithLabelValues(backendName).Inc()
+}
+
+func (b *Backend) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.ID == s.State.User.ID {
+		// ボット自身の発言は無視
+		return
+	}
+
+	mention := fmt.Sprintf("<@%s>", s.State.User.ID)
+	if strings.Contains(m.Content, mention) {
+		b.events <- chat.Event{Type: chat.EventTypeMention, ChannelID: m.ChannelID, UserID: m.Author.ID, Text: m.Content}
+		return
+	}
+
+	b.events <- chat.Event{Type: chat.EventTypeMessage, ChannelID: m.ChannelID, UserID: m.Author.ID, Text: m.Content}
+}
+
+func (b *Backend) SendMessage(channelID, text string) error {
+	_, err := b.session.ChannelMessageSend(channelID, text)
+	if err != nil {
+		metrics.APIErrorsTotal.WithLabelValues(backendName).Inc()
+	}
+	return err
+}
+
+func (b *Backend) LookupUser(userID string) (string, error) {
+	user, err := b.session.User(userID)
+	if err != nil {
+		metrics.APIErrorsTotal.WithLabelValues(backendName).Inc()
+		return "", err
+	}
+	return user.Username, nil
+}
+
+func (b *Backend) Events() <-chan chat.Event {
+	return b.events
+}
+
+func (b *Backend) Close() error {
+	close(b.events)
+	return b.session.Close()
+}
+
+// Run はDiscordBackendの場合、接続はNewの時点で確立済みのため何もせずブロックするだけです。
+func (b *Backend) Run() {
+	select {}
+}
+
+// Session は基盤となる*discordgo.Sessionを返します。
+func (b *Backend) Session() *discordgo.Session {
+	return b.session
+}
+
+// BotUserID はゲートウェイ接続時に取得したボット自身のユーザーIDを返します。
+func (b *Backend) BotUserID() (string, error) {
+	if b.session.State == nil || b.session.State.User == nil {
+		return "", fmt.Errorf("ボットのユーザー情報がまだ取得できていません")
+	}
+	return b.session.State.User.ID, nil
+}
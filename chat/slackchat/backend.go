@@ -0,0 +1,144 @@
+// Package slackchat はSlackのSocket Mode/Events APIをラップした chat.Backend 実装を提供します。
+package slackchat
+
+import (
+	"log"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+
+	"hihumikan/master-card-manager/chat"
+	"hihumikan/master-card-manager/metrics"
+)
+
+const backendName = "slack"
+
+// SlashCommandHandler はスラッシュコマンドを処理し、エフェメラルな応答ペイロードを返します。
+type SlashCommandHandler func(slack.SlashCommand) map[string]interface{}
+
+// Backend は chat.Backend の Slack実装です。Socket Mode経由でイベントを受信します。
+// Block Kit・モーダル・スラッシュコマンドの配信などSlack固有の機能は、Client()で取得できる
+// 生の*slack.Clientや、SetSlashCommandHandlerのようなSlack固有のメソッドを通じて利用します。
+type Backend struct {
+	api                 *slack.Client
+	client              *socketmode.Client
+	events              chan chat.Event
+	slashCommandHandler SlashCommandHandler
+}
+
+// New はtoken(bot token)とappToken(app-level token、Socket Mode用)からBackendを作成します。
+func New(token, appToken string) *Backend {
+	api := slack.New(token, slack.OptionAppLevelToken(appToken))
+	return &Backend{
+		api:    api,
+		client: socketmode.New(api),
+		events: make(chan chat.Event, 64),
+	}
+}
+
+// Client は基盤となる*slack.Clientを返します。Block Kitの投稿やモーダル表示など、
+// chat.Backendで表現できないSlack固有機能を呼び出すために使います。
+func (b *Backend) Client() *slack.Client {
+	return b.api
+}
+
+// BotUserID はAuthTestで取得したボット自身のユーザーIDを返します。
+func (b *Backend) BotUserID() (string, error) {
+	authTest, err := b.api.AuthTest()
+	if err != nil {
+		return "", err
+	}
+	return authTest.UserID, nil
+}
+
+// SetSlashCommandHandler はスラッシュコマンド受信時のハンドラを登録します。
+func (b *Backend) SetSlashCommandHandler(handler SlashCommandHandler) {
+	b.slashCommandHandler = handler
+}
+
+func (b *Backend) SendMessage(channelID, text string) error {
+	_, _, err := b.api.PostMessage(channelID, slack.MsgOptionText(text, false))
+	if err != nil {
+		metrics.APIErrorsTotal.WithLabelValues(backendName).Inc()
+	}
+	return err
+}
+
+func (b *Backend) LookupUser(userID string) (string, error) {
+	user, err := b.api.GetUserInfo(userID)
+	if err != nil {
+		metrics.APIErrorsTotal.WithLabelValues(backendName).Inc()
+		return "", err
+	}
+	return user.RealName, nil
+}
+
+func (b *Backend) Events() <-chan chat.Event {
+	return b.events
+}
+
+func (b *Backend) Close() error {
+	close(b.events)
+	return nil
+}
+
+// Run はSocket Modeの受信ループを起動します。呼び出し元をブロックします。
+func (b *Backend) Run() {
+	go func() {
+		for evt := range b.client.Events {
+			switch evt.Type {
+			case socketmode.EventTypeConnecting:
+				log.Println("Socket Modeで接続しています...")
+			case socketmode.EventTypeConnectionError:
+				log.Println("接続に失敗しました。再試行します...")
+				metrics.APIErrorsTotal.WithLabelValues(backendName).Inc()
+			case socketmode.EventTypeConnected:
+				log.Println("Socket Modeで接続されました。")
+				metrics.ReconnectsTotal.WithLabelValues(backendName).Inc()
+			case socketmode.EventTypeEventsAPI:
+				eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
+				if !ok {
+					log.Printf("Ignored unexpected EventsAPI payload: %v\n", evt)
+					continue
+				}
+				b.client.Ack(*evt.Request)
+				b.dispatchEventsAPI(eventsAPIEvent)
+			case socketmode.EventTypeSlashCommand:
+				cmd, ok := evt.Data.(slack.SlashCommand)
+				if !ok {
+					log.Printf("Ignored unexpected slash command payload: %v\n", evt)
+					continue
+				}
+				b.client.Ack(*evt.Request, b.handleSlashCommand(cmd))
+			default:
+				// 他のイベントは無視
+			}
+		}
+	}()
+
+	b.client.Run()
+}
+
+func (b *Backend) dispatchEventsAPI(event slackevents.EventsAPIEvent) {
+	if event.Type != slackevents.CallbackEvent {
+		return
+	}
+
+	switch ev := event.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		b.events <- chat.Event{Type: chat.EventTypeMessage, ChannelID: ev.Channel, UserID: ev.User, Text: ev.Text}
+	case *slackevents.AppMentionEvent:
+		b.events <- chat.Event{Type: chat.EventTypeMention, ChannelID: ev.Channel, UserID: ev.User, Text: ev.Text}
+	}
+}
+
+func (b *Backend) handleSlashCommand(cmd slack.SlashCommand) map[string]interface{} {
+	if b.slashCommandHandler == nil {
+		return map[string]interface{}{
+			"response_type": "ephemeral",
+			"text":          "このコマンドはまだ利用できません。",
+		}
+	}
+	return b.slashCommandHandler(cmd)
+}
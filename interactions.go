@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// borrowModalMetadata は貸し出しモーダルのPrivateMetadataに載せる情報です。
+// モーダル送信時に、どのカードのどのステータスメッセージを更新すべきかを復元するために使います。
+type borrowModalMetadata struct {
+	CardID    string `json:"card_id"`
+	ChannelID string `json:"channel_id"`
+	MessageTS string `json:"message_ts"`
+}
+
+// runInteractionsServer はSlackのインタラクティブコンポーネント用Request URLを待ち受けます。
+func (b *Bot) runInteractionsServer(port string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack/interactions", b.handleInteraction)
+
+	addr := fmt.Sprintf(":%s", port)
+	log.Printf("Listening for Slack interactions on %s\n", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("インタラクションサーバーの起動に失敗しました: %v", err)
+	}
+}
+
+// handleInteraction はSlackの署名を検証した上で、block_actions / view_submission を振り分けます。
+func (b *Bot) handleInteraction(w http.ResponseWriter, r *http.Request) {
+	verifier, err := slack.NewSecretsVerifier(r.Header, b.signingSecret)
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(io.TeeReader(r.Body, &verifier))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := verifier.Ensure(); err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var payload slack.InteractionCallback
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &payload); err != nil {
+		http.Error(w, "failed to parse payload", http.StatusBadRequest)
+		return
+	}
+
+	switch payload.Type {
+	case slack.InteractionTypeBlockActions:
+		b.handleBlockAction(payload)
+	case slack.InteractionTypeViewSubmission:
+		b.handleViewSubmission(payload)
+	default:
+		log.Printf("Ignored interaction type: %s\n", payload.Type)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleBlockAction は借りる/返すボタンの押下を処理します。
+func (b *Bot) handleBlockAction(payload slack.InteractionCallback) {
+	for _, action := range payload.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case "borrow_key":
+			metadata, err := json.Marshal(borrowModalMetadata{
+				CardID:    action.Value,
+				ChannelID: payload.Channel.ID,
+				MessageTS: payload.Message.Timestamp,
+			})
+			if err != nil {
+				log.Printf("Failed to encode borrow modal metadata: %v\n", err)
+				continue
+			}
+			if _, err := b.slack.Client().OpenView(payload.TriggerID, buildBorrowModal(string(metadata))); err != nil {
+				log.Printf("Failed to open borrow modal: %v\n", err)
+			}
+		case "return_key":
+			result := b.doReturn(action.Value, payload.User.ID)
+			postEphemeralResponse(payload.ResponseURL, result)
+			b.updateStatusMessage(payload.Channel.ID, payload.Message.Timestamp)
+		}
+	}
+}
+
+// handleViewSubmission は貸し出しモーダルの送信(返却予定日の入力)を処理します。
+func (b *Bot) handleViewSubmission(payload slack.InteractionCallback) {
+	if payload.View.CallbackID != "borrow_modal" {
+		return
+	}
+
+	var metadata borrowModalMetadata
+	if err := json.Unmarshal([]byte(payload.View.PrivateMetadata), &metadata); err != nil {
+		log.Printf("Failed to decode borrow modal metadata: %v\n", err)
+		return
+	}
+
+	var expectedReturn time.Time
+	if state := payload.View.State; state != nil {
+		if selectedDate := state.Values["expected_return_block"]["expected_return_date"].SelectedDate; selectedDate != "" {
+			parsed, err := time.Parse("2006-01-02", selectedDate)
+			if err != nil {
+				log.Printf("Failed to parse expected return date %q: %v\n", selectedDate, err)
+			} else {
+				expectedReturn = parsed
+			}
+		}
+	}
+
+	result := b.doBorrow(metadata.CardID, payload.User.ID, expectedReturn)
+	postEphemeralResponse(payload.ResponseURL, result)
+	if metadata.ChannelID != "" && metadata.MessageTS != "" {
+		b.updateStatusMessage(metadata.ChannelID, metadata.MessageTS)
+	}
+}
+
+// postEphemeralResponse はresponseURL(block_actions/view_submissionのコールバックに含まれる
+// response_url)へ、本人にしか見えないエフェメラルなテキストを返します。借りる/返すの結果を
+// ボタン操作・モーダル送信の双方でユーザーに伝えるために使います。
+func postEphemeralResponse(responseURL, text string) {
+	if responseURL == "" {
+		return
+	}
+	if err := slack.PostWebhook(responseURL, &slack.WebhookMessage{
+		ResponseType: "ephemeral",
+		Text:         text,
+	}); err != nil {
+		log.Printf("Failed to post ephemeral response: %v\n", err)
+	}
+}
+
+// updateStatusMessage は既存の貸し出し状況メッセージを最新の状態で置き換えます。
+func (b *Bot) updateStatusMessage(channelID, timestamp string) {
+	if _, _, _, err := b.slack.Client().UpdateMessage(channelID, timestamp, slack.MsgOptionBlocks(b.buildStatusBlocks()...)); err != nil {
+		log.Printf("Failed to update status message in %s: %v\n", channelID, err)
+	}
+}
+
+// buildBorrowModal は返却予定日を尋ねる貸し出し確認モーダルを組み立てます。
+func buildBorrowModal(privateMetadata string) slack.ModalViewRequest {
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      "borrow_modal",
+		PrivateMetadata: privateMetadata,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "カードを借りる", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "借りる", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "キャンセル", false, false),
+		Blocks: slack.Blocks{
+			BlockSet: []slack.Block{
+				slack.NewInputBlock(
+					"expected_return_block",
+					slack.NewTextBlockObject(slack.PlainTextType, "返却予定日", false, false),
+					nil,
+					slack.NewDatePickerBlockElement("expected_return_date"),
+				),
+			},
+		},
+	}
+}
@@ -0,0 +1,157 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Card は1枚のカードの設定を表します。
+type Card struct {
+	ID           string `yaml:"id" json:"id"`
+	Label        string `yaml:"label" json:"label"`
+	OwnerGroup   string `yaml:"owner_group,omitempty" json:"owner_group,omitempty"`
+	MaxLoanHours int    `yaml:"max_loan_hours,omitempty" json:"max_loan_hours,omitempty"`
+}
+
+// ScheduleTarget はScheduleRuleの通知先の種類です。
+type ScheduleTarget string
+
+const (
+	// ScheduleTargetChannel はボットの常駐チャンネルへの投稿です(例: 毎朝のダイジェスト)。
+	ScheduleTargetChannel ScheduleTarget = "channel"
+	// ScheduleTargetDM は借り主個人へのダイレクトメッセージです。
+	ScheduleTargetDM ScheduleTarget = "dm"
+	// ScheduleTargetMention はチャンネルへの@hereなどを含むエスカレーション投稿です。
+	ScheduleTargetMention ScheduleTarget = "mention"
+)
+
+// ScheduleRule はcron.Cronで実行される1つの通知ルールを表します。
+// Cronはこのルールを評価する頻度で、AfterHoursはDM/mentionの通知対象を絞り込む
+// 「借りてから何時間経過したか」のしきい値です(channelのダイジェストでは使いません)。
+type ScheduleRule struct {
+	Name       string         `yaml:"name" json:"name"`
+	Cron       string         `yaml:"cron" json:"cron"`
+	Target     ScheduleTarget `yaml:"target" json:"target"`
+	Template   string         `yaml:"template" json:"template"`
+	AfterHours int            `yaml:"after_hours,omitempty" json:"after_hours,omitempty"`
+}
+
+// defaultTimezone はTimezoneが未指定の場合にschedulesの評価に使うタイムゾーンです。
+const defaultTimezone = "Asia/Tokyo"
+
+// Inventory はCARDS_CONFIGで指定される設定ファイルの内容を表します。
+type Inventory struct {
+	Cards     []Card         `yaml:"cards" json:"cards"`
+	Schedules []ScheduleRule `yaml:"schedules,omitempty" json:"schedules,omitempty"`
+	// Timezone はSchedulesのcron式を評価するタイムゾーンです(例: "Asia/Tokyo")。
+	// 空の場合はAsia/Tokyoとして扱われます。
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
+// Load はpathのYAML/JSONファイルを読み込み、バリデーション済みのInventoryを返します。
+// 拡張子が .json のものはJSONとして、それ以外はYAMLとして解釈します。
+func Load(path string) (*Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("カード設定ファイルを読み込めませんでした: %v", err)
+	}
+
+	var inv Inventory
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &inv)
+	} else {
+		err = yaml.Unmarshal(data, &inv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("カード設定ファイルの解析に失敗しました: %v", err)
+	}
+
+	if err := inv.validate(); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (inv *Inventory) validate() error {
+	if len(inv.Cards) == 0 {
+		return fmt.Errorf("カード設定に1件もカードが定義されていません")
+	}
+
+	seen := make(map[string]bool, len(inv.Cards))
+	for _, card := range inv.Cards {
+		if card.ID == "" {
+			return fmt.Errorf("idが空のカード定義があります")
+		}
+		if card.Label == "" {
+			return fmt.Errorf("カード%sのlabelが空です", card.ID)
+		}
+		if seen[card.ID] {
+			return fmt.Errorf("カードID %sが重複しています", card.ID)
+		}
+		seen[card.ID] = true
+	}
+
+	if _, err := inv.Location(); err != nil {
+		return fmt.Errorf("timezoneが不正です: %v", err)
+	}
+
+	seenRules := make(map[string]bool, len(inv.Schedules))
+	for _, rule := range inv.Schedules {
+		if rule.Name == "" {
+			return fmt.Errorf("nameが空のスケジュールルールがあります")
+		}
+		if seenRules[rule.Name] {
+			return fmt.Errorf("スケジュールルール名 %sが重複しています", rule.Name)
+		}
+		seenRules[rule.Name] = true
+
+		if _, err := cron.ParseStandard(rule.Cron); err != nil {
+			return fmt.Errorf("スケジュールルール%sのcron式が不正です: %v", rule.Name, err)
+		}
+		switch rule.Target {
+		case ScheduleTargetChannel, ScheduleTargetDM, ScheduleTargetMention:
+		default:
+			return fmt.Errorf("スケジュールルール%sのtargetが不正です: %s", rule.Name, rule.Target)
+		}
+		if rule.Template == "" {
+			return fmt.Errorf("スケジュールルール%sのtemplateが空です", rule.Name)
+		}
+	}
+	return nil
+}
+
+// Card はidに対応するカード定義を返します。見つからない場合はokがfalseになります。
+func (inv *Inventory) Card(id string) (Card, bool) {
+	for _, card := range inv.Cards {
+		if card.ID == id {
+			return card, true
+		}
+	}
+	return Card{}, false
+}
+
+// Location はTimezoneを*time.Locationとして返します。Timezoneが未指定の場合はAsia/Tokyoを使います。
+func (inv *Inventory) Location() (*time.Location, error) {
+	name := inv.Timezone
+	if name == "" {
+		name = defaultTimezone
+	}
+	return time.LoadLocation(name)
+}
+
+// MaxLoanDuration はidのカードに設定された貸出上限時間を返します。
+// カードにmax_loan_hoursの指定がなければdefaultDurationを返します。
+func (inv *Inventory) MaxLoanDuration(id string, defaultDuration time.Duration) time.Duration {
+	card, ok := inv.Card(id)
+	if !ok || card.MaxLoanHours <= 0 {
+		return defaultDuration
+	}
+	return time.Duration(card.MaxLoanHours) * time.Hour
+}
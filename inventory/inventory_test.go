@@ -0,0 +1,189 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	return path
+}
+
+const validYAML = `
+cards:
+  - id: "13"
+    label: "マスターキー13"
+    max_loan_hours: 48
+  - id: "14"
+    label: "マスターキー14"
+schedules:
+  - name: daily_digest
+    cron: "0 9 * * *"
+    target: channel
+    template: "digest"
+`
+
+func TestLoadValidYAML(t *testing.T) {
+	path := writeConfig(t, "cards.yaml", validYAML)
+
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(inv.Cards) != 2 {
+		t.Fatalf("expected 2 cards, got %d", len(inv.Cards))
+	}
+
+	card, ok := inv.Card("13")
+	if !ok {
+		t.Fatalf("expected card 13 to exist")
+	}
+	if card.Label != "マスターキー13" {
+		t.Errorf("Label = %q, want %q", card.Label, "マスターキー13")
+	}
+}
+
+func TestLoadValidJSON(t *testing.T) {
+	const jsonConfig = `{
+		"cards": [{"id": "13", "label": "マスターキー13"}]
+	}`
+	path := writeConfig(t, "cards.json", jsonConfig)
+
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(inv.Cards) != 1 {
+		t.Fatalf("expected 1 card, got %d", len(inv.Cards))
+	}
+}
+
+func TestLoadRejectsEmptyCards(t *testing.T) {
+	path := writeConfig(t, "cards.yaml", "cards: []\n")
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for a config with no cards, got nil")
+	}
+}
+
+func TestLoadRejectsDuplicateCardID(t *testing.T) {
+	const dup = `
+cards:
+  - id: "13"
+    label: "マスターキー13"
+  - id: "13"
+    label: "マスターキー13(重複)"
+`
+	path := writeConfig(t, "cards.yaml", dup)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for duplicate card IDs, got nil")
+	}
+}
+
+func TestLoadRejectsInvalidCron(t *testing.T) {
+	const badCron = `
+cards:
+  - id: "13"
+    label: "マスターキー13"
+schedules:
+  - name: bad_rule
+    cron: "not a cron expression"
+    target: channel
+    template: "x"
+`
+	path := writeConfig(t, "cards.yaml", badCron)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for an invalid cron expression, got nil")
+	}
+}
+
+func TestLoadRejectsInvalidScheduleTarget(t *testing.T) {
+	const badTarget = `
+cards:
+  - id: "13"
+    label: "マスターキー13"
+schedules:
+  - name: bad_rule
+    cron: "0 9 * * *"
+    target: pigeon
+    template: "x"
+`
+	path := writeConfig(t, "cards.yaml", badTarget)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for an invalid schedule target, got nil")
+	}
+}
+
+func TestLoadRejectsInvalidTimezone(t *testing.T) {
+	const badTZ = `
+timezone: Not/ARealZone
+cards:
+  - id: "13"
+    label: "マスターキー13"
+`
+	path := writeConfig(t, "cards.yaml", badTZ)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for an invalid timezone, got nil")
+	}
+}
+
+func TestLocationDefaultsToAsiaTokyo(t *testing.T) {
+	path := writeConfig(t, "cards.yaml", validYAML)
+
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	loc, err := inv.Location()
+	if err != nil {
+		t.Fatalf("Location failed: %v", err)
+	}
+	if loc.String() != "Asia/Tokyo" {
+		t.Errorf("Location = %q, want %q", loc.String(), "Asia/Tokyo")
+	}
+}
+
+func TestMaxLoanDuration(t *testing.T) {
+	path := writeConfig(t, "cards.yaml", validYAML)
+
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if got := inv.MaxLoanDuration("13", 24*time.Hour); got != 48*time.Hour {
+		t.Errorf("MaxLoanDuration(13) = %v, want %v (card override)", got, 48*time.Hour)
+	}
+	if got := inv.MaxLoanDuration("14", 24*time.Hour); got != 24*time.Hour {
+		t.Errorf("MaxLoanDuration(14) = %v, want %v (falls back to default)", got, 24*time.Hour)
+	}
+	if got := inv.MaxLoanDuration("99", 24*time.Hour); got != 24*time.Hour {
+		t.Errorf("MaxLoanDuration(99) = %v, want %v (unknown card falls back to default)", got, 24*time.Hour)
+	}
+}
+
+func TestCardNotFound(t *testing.T) {
+	path := writeConfig(t, "cards.yaml", validYAML)
+
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if _, ok := inv.Card("99"); ok {
+		t.Error("expected Card(99) to report ok=false for an unknown card")
+	}
+}
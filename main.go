@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
@@ -10,56 +12,160 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
 	"github.com/slack-go/slack"
+
+	"hihumikan/master-card-manager/chat"
+	"hihumikan/master-card-manager/chat/discordchat"
+	"hihumikan/master-card-manager/chat/slackchat"
+	"hihumikan/master-card-manager/inventory"
+	"hihumikan/master-card-manager/metrics"
+	"hihumikan/master-card-manager/storage"
 )
 
+// defaultMaxLoanDuration はカード設定でmax_loan_hoursが指定されていない場合に使われる貸出上限です。
+const defaultMaxLoanDuration = 48 * time.Hour
+
+// eventLogger はhandleMessage/doBorrow/doReturn/runPerLoanRuleが発行する、
+// key_num/user_id/actionフィールド付きの構造化ログ(JSON)を出力します。
+var eventLogger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
 // KeyStatus は各キーの貸し出し状況を表します。
+// ExpectedReturn は貸し出しモーダルで返却予定日が指定された場合のみ設定されます。
 type KeyStatus struct {
-	KeyNumber  string
-	Borrower   string
-	BorrowedAt time.Time
+	KeyNumber      string
+	Borrower       string
+	BorrowedAt     time.Time
+	ExpectedReturn time.Time
 }
 
-// Bot はSlackボットの構造体です。
+// Bot はチャットサービスに依存しないボット本体です。Backendを通じてメッセージの
+// 送受信を行い、Block Kitやモーダルなど実装固有の機能を使うときだけslack
+// (非nilならSlack Backend)を直接参照します。
 type Bot struct {
-	api         *slack.Client
-	keyStatuses map[string]*KeyStatus
-	mutex       sync.Mutex
-	channelID   string
-	botUserID   string
+	backend       chat.Backend
+	slack         *slackchat.Backend
+	store         storage.Store
+	keyStatuses   map[string]*KeyStatus
+	mutex         sync.Mutex
+	cardsConfig   string
+	inventory     *inventory.Inventory
+	inventoryMu   sync.RWMutex
+	cron          *cron.Cron
+	signingSecret string
+	channelID     string
+	botUserID     string
 }
 
-// NewBot は新しいBotインスタンスを作成します。
-func NewBot(token, channelName string) (*Bot, error) {
-	api := slack.New(token)
+// NewBot は新しいBotインスタンスを作成します。dbPath の示すSQLiteファイルを開き、
+// 既存の貸し出し状況を読み込んだ上でBotを初期化します。cardsConfig はカード一覧の
+// 設定ファイルパスで、inventory.Inventoryとしてロード・バリデーションされます。backendは
+// CHAT_BACKENDに応じてmain()が初期化済みの状態で渡し(SlackのSocket Mode接続はRunで開始)、slackBackendはSlack固有機能用に
+// backendがSlackの場合のみ非nilで渡します。
+func NewBot(backend chat.Backend, slackBackend *slackchat.Backend, channelID, botUserID, dbPath, cardsConfig, signingSecret string) (*Bot, error) {
+	store, err := storage.NewSQLiteStore(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("ストレージの初期化に失敗しました: %v", err)
+	}
 
-	// チャンネル名からチャンネルIDを取得
-	channelID, err := getChannelID(api, channelName)
+	cardInventory, err := inventory.Load(cardsConfig)
 	if err != nil {
-		return nil, err
+		store.Close()
+		return nil, fmt.Errorf("カード設定の読み込みに失敗しました: %v", err)
+	}
+
+	// 再起動してもアクティブな貸し出しと延滞チェックが途切れないよう、DBから状態を復元する
+	activeLoans, err := store.ActiveLoans()
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("貸し出し状況の復元に失敗しました: %v", err)
+	}
+
+	keyStatuses := make(map[string]*KeyStatus, len(activeLoans))
+	for keyNum, loan := range activeLoans {
+		keyStatuses[keyNum] = &KeyStatus{
+			KeyNumber:      loan.KeyNumber,
+			Borrower:       loan.Borrower,
+			BorrowedAt:     loan.BorrowedAt,
+			ExpectedReturn: loan.ExpectedReturn,
+		}
+		metrics.LoanedCards.WithLabelValues(keyNum).Set(1)
 	}
 
-	// チャンネルに参加
-	err = joinChannel(api, channelID)
+	return &Bot{
+		backend:       backend,
+		slack:         slackBackend,
+		store:         store,
+		keyStatuses:   keyStatuses,
+		cardsConfig:   cardsConfig,
+		inventory:     cardInventory,
+		signingSecret: signingSecret,
+		channelID:     channelID,
+		botUserID:     botUserID,
+	}, nil
+}
+
+// newSlackBackend はチャンネル名からチャンネルIDを解決し、参加した上でSlack Backendを作成します。
+func newSlackBackend(token, appToken, channelName string) (backend *slackchat.Backend, channelID, botUserID string, err error) {
+	backend = slackchat.New(token, appToken)
+	api := backend.Client()
+
+	channelID, err = getChannelID(api, channelName)
 	if err != nil {
+		return nil, "", "", err
+	}
+
+	if err := joinChannel(api, channelID); err != nil {
 		// チャンネルに既に参加している場合のエラーを無視
 		if !strings.Contains(err.Error(), "already_in_channel") {
-			return nil, fmt.Errorf("チャンネルに参加できませんでした: %v", err)
+			return nil, "", "", fmt.Errorf("チャンネルに参加できませんでした: %v", err)
 		}
 	}
 
-	// ボットのユーザーIDを取得
-	authTest, err := api.AuthTest()
+	botUserID, err = backend.BotUserID()
 	if err != nil {
-		return nil, fmt.Errorf("AuthTest failed: %v", err)
+		return nil, "", "", fmt.Errorf("AuthTest failed: %v", err)
 	}
 
-	return &Bot{
-		api:         api,
-		keyStatuses: make(map[string]*KeyStatus),
-		channelID:   channelID,
-		botUserID:   authTest.UserID,
-	}, nil
+	return backend, channelID, botUserID, nil
+}
+
+// newDiscordBackend はゲートウェイへ接続済みのDiscord Backendを作成します。
+// Discordの場合、チャンネルIDは名前解決を行わずDISCORD_CHANNEL_IDでそのまま指定します。
+func newDiscordBackend(token, channelID string) (backend *discordchat.Backend, botUserID string, err error) {
+	backend, err = discordchat.New(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	botUserID, err = backend.BotUserID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return backend, botUserID, nil
+}
+
+// reloadInventory はcardsConfigを再読み込みし、Botの保持する在庫情報を差し替えます。
+func (b *Bot) reloadInventory() error {
+	newInventory, err := inventory.Load(b.cardsConfig)
+	if err != nil {
+		return err
+	}
+
+	b.inventoryMu.Lock()
+	b.inventory = newInventory
+	b.inventoryMu.Unlock()
+
+	b.reloadScheduler()
+	return nil
+}
+
+// cardInventory は現在の在庫情報を取得します。
+func (b *Bot) cardInventory() *inventory.Inventory {
+	b.inventoryMu.RLock()
+	defer b.inventoryMu.RUnlock()
+	return b.inventory
 }
 
 // getChannelID はチャンネル名からチャンネルIDを取得します。
@@ -87,102 +193,141 @@ func joinChannel(api *slack.Client, channelID string) error {
 	return err
 }
 
-// listChannels はボットがアクセスできるチャンネルの一覧をログに出力します。
-func listChannels(api *slack.Client) {
-	params := slack.GetConversationsParameters{
-		Limit: 1000,
-		Types: []string{"public_channel", "private_channel"},
-	}
-	channels, _, err := api.GetConversations(&params)
-	if err != nil {
-		log.Fatalf("チャンネルリストの取得に失敗しました: %v", err)
-	}
-
-	log.Println("アクセス可能なチャンネル一覧:")
-	for _, ch := range channels {
-		log.Printf("- %s (%s)\n", ch.Name, ch.ID)
-	}
+// runner はBackendのうち、自らブロックしてイベントを配信し続けるものが実装するインターフェースです。
+type runner interface {
+	Run()
 }
 
-// Run はボットを起動します。
+// Run はBackend経由でイベントを受信し、ボットを起動します。
 func (b *Bot) Run() {
-	for {
-		rtm := b.api.NewRTM()
-		go rtm.ManageConnection()
-
-		// 定期的に過去2日以上返却されていないキーをチェック
-		go b.overdueChecker()
-
-		for msg := range rtm.IncomingEvents {
-			switch ev := msg.Data.(type) {
-			case *slack.MessageEvent:
-				log.Printf("Message Event received: ChannelID=%s, UserID=%s, Text=%s\n", ev.Channel, ev.User, ev.Text)
-				b.handleMessage(ev, rtm)
-			case *slack.InvalidAuthEvent:
-				log.Fatalf("Invalid credentials")
-			case *slack.RTMError:
-				log.Printf("RTM error: %s\n", ev.Error())
-			case *slack.ConnectionErrorEvent:
-				log.Printf("Connection error: %s\n", ev.Error())
-			default:
-				// 他のイベントは無視
+	// カード設定のschedulesに従って、ダイジェストや延滞リマインドを定期実行する
+	b.startScheduler()
+
+	if b.slack != nil {
+		b.slack.SetSlashCommandHandler(b.handleSlashCommand)
+	}
+
+	go func() {
+		for evt := range b.backend.Events() {
+			switch evt.Type {
+			case chat.EventTypeMessage:
+				log.Printf("Message Event received: ChannelID=%s, UserID=%s, Text=%s\n", evt.ChannelID, evt.UserID, evt.Text)
+				b.handleMessage(evt.Text, evt.UserID, evt.ChannelID)
+			case chat.EventTypeMention:
+				log.Println("Bot was mentioned. Reporting status.")
+				b.reportStatus(evt.ChannelID)
 			}
 		}
+	}()
 
-		log.Println("RTM connection closed. Reconnecting in 5 seconds...")
-		time.Sleep(5 * time.Second) // 遅延後に再接続
+	log.Println("ボットを起動します...")
+	if r, ok := b.backend.(runner); ok {
+		r.Run()
+		return
 	}
+	select {}
 }
 
-func (b *Bot) handleMessage(ev *slack.MessageEvent, rtm *slack.RTM) {
-	text := ev.Text
-	user := ev.User
+// handleSlashCommand はスラッシュコマンドを処理し、エフェメラルな応答ペイロードを返します。
+func (b *Bot) handleSlashCommand(cmd slack.SlashCommand) map[string]interface{} {
+	arg := strings.TrimSpace(cmd.Text)
+
+	var text string
+	switch cmd.Command {
+	case "/borrow":
+		text = b.doBorrow(arg, cmd.UserID, time.Time{})
+	case "/return":
+		text = b.doReturn(arg, cmd.UserID)
+	case "/status":
+		text = b.doStatus()
+	case "/history":
+		text = b.doHistory(arg)
+	case "/reload":
+		if err := b.reloadInventory(); err != nil {
+			text = fmt.Sprintf("カード設定の再読み込みに失敗しました: %v", err)
+		} else {
+			text = "カード設定を再読み込みしました。"
+		}
+	case "/schedule":
+		text = formatScheduleList(b.cardInventory().Schedules)
+	default:
+		text = fmt.Sprintf("不明なコマンドです: %s", cmd.Command)
+	}
 
-	log.Printf("Handling message: %s from user: %s in channel: %s\n", text, user, ev.Channel)
+	return map[string]interface{}{
+		"response_type": "ephemeral",
+		"text":          text,
+	}
+}
+
+// handleMessage はメッセージイベントのテキストを正規表現で解析します。
+// スラッシュコマンドへの移行後も、チャンネルでの自然文入力との互換性のために残しています。
+func (b *Bot) handleMessage(text, user, channel string) {
+	eventLogger.Info("message received", "user_id", user, "action", "message_received")
 
 	// 対象のチャンネル以外のメッセージは無視
-	if ev.Channel != b.channelID {
-		log.Println("Message is not in the target channel. Ignoring.")
+	if channel != b.channelID {
+		eventLogger.Info("message ignored: not the target channel", "user_id", user, "action", "message_ignored")
 		return
 	}
 
 	// メッセージ内容を解析
 	borrowRegex := regexp.MustCompile(`(?i)(\d{2})\s*番?\s*(借ります|借りる|借りたい)`)
 	returnRegex := regexp.MustCompile(`(?i)(\d{2})\s*番?\s*(返します|返す|返却します)`)
+	historyRegex := regexp.MustCompile(`(?i)(\d{2})\s*番?\s*(履歴|history)`)
 
 	if matches := borrowRegex.FindStringSubmatch(text); len(matches) >= 3 {
 		keyNum := matches[1]
-		log.Printf("Detected borrow command for key: %s\n", keyNum)
-		b.borrowKey(keyNum, user, rtm)
+		eventLogger.Info("detected borrow command", "key_num", keyNum, "user_id", user, "action", "borrow")
+		b.postMessage(channel, b.doBorrow(keyNum, user, time.Time{}))
 		return
 	}
 
 	if matches := returnRegex.FindStringSubmatch(text); len(matches) >= 3 {
 		keyNum := matches[1]
-		log.Printf("Detected return command for key: %s\n", keyNum)
-		b.returnKey(keyNum, user, rtm)
+		eventLogger.Info("detected return command", "key_num", keyNum, "user_id", user, "action", "return")
+		b.postMessage(channel, b.doReturn(keyNum, user))
 		return
 	}
 
-	// ボットがメンションされた場合、状態を報告
-	mention := fmt.Sprintf("<@%s>", b.botUserID)
-	if strings.Contains(text, mention) {
-		log.Println("Bot was mentioned. Reporting status.")
-		b.reportStatus(rtm, ev.Channel)
+	if matches := historyRegex.FindStringSubmatch(text); len(matches) >= 3 {
+		keyNum := matches[1]
+		eventLogger.Info("detected history command", "key_num", keyNum, "user_id", user, "action", "history")
+		b.postMessage(channel, b.doHistory(keyNum))
 		return
 	}
 
-	log.Println("No actionable command detected in the message.")
+	eventLogger.Info("no actionable command detected", "user_id", user, "action", "noop")
+}
+
+// postMessage は指定したチャンネルにテキストメッセージを投稿します。
+func (b *Bot) postMessage(channelID, text string) error {
+	if err := b.backend.SendMessage(channelID, text); err != nil {
+		log.Printf("Failed to post message to %s: %v\n", channelID, err)
+		return err
+	}
+	return nil
+}
+
+// reportStatus はチャンネルに貸し出し状況を報告します。SlackではBlock Kitの
+// ボタン付きメッセージを、それ以外のBackendではdoStatusのプレーンテキストを投稿します。
+func (b *Bot) reportStatus(channelID string) {
+	if b.slack != nil {
+		b.postStatusBlocks(channelID)
+		return
+	}
+	b.postMessage(channelID, b.doStatus())
 }
 
-// borrowKey は指定されたキーを借りる処理を行います。
-func (b *Bot) borrowKey(keyNum, user string, rtm *slack.RTM) {
+// doBorrow は指定されたキーを借りる処理を行い、結果メッセージを返します。
+// expectedReturn がゼロ値でない場合、貸し出しモーダルで指定された返却予定日として記録されます。
+func (b *Bot) doBorrow(keyNum, user string, expectedReturn time.Time) string {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	if keyNum != "13" && keyNum != "14" && keyNum != "15" {
-		rtm.SendMessage(rtm.NewOutgoingMessage(fmt.Sprintf("カード番号が無効です: %s", keyNum), b.channelID))
-		return
+	card, ok := b.cardInventory().Card(keyNum)
+	if !ok {
+		return fmt.Sprintf("カード番号が無効です: %s", keyNum)
 	}
 
 	if status, exists := b.keyStatuses[keyNum]; exists {
@@ -191,32 +336,45 @@ func (b *Bot) borrowKey(keyNum, user string, rtm *slack.RTM) {
 		if err != nil {
 			userName = status.Borrower
 		}
-		rtm.SendMessage(rtm.NewOutgoingMessage(fmt.Sprintf("カード番号%sは既に%sさんが借りています。", keyNum, userName), b.channelID))
-		return
+		return fmt.Sprintf("カード番号%sは既に%sさんが借りています。", keyNum, userName)
+	}
+
+	borrowedAt := time.Now()
+	if err := b.store.BorrowKey(keyNum, user, borrowedAt, expectedReturn); err != nil {
+		eventLogger.Error("failed to persist borrow", "key_num", keyNum, "user_id", user, "action", "borrow", "error", err)
+		return "貸し出し状況の保存に失敗しました。もう一度お試しください。"
 	}
 
 	b.keyStatuses[keyNum] = &KeyStatus{
-		KeyNumber:  keyNum,
-		Borrower:   user,
-		BorrowedAt: time.Now(),
+		KeyNumber:      keyNum,
+		Borrower:       user,
+		BorrowedAt:     borrowedAt,
+		ExpectedReturn: expectedReturn,
 	}
 
+	metrics.BorrowsTotal.Inc()
+	metrics.LoanedCards.WithLabelValues(keyNum).Set(1)
+	eventLogger.Info("key borrowed", "key_num", keyNum, "user_id", user, "action", "borrow")
+
 	userName, err := b.getUserName(user)
 	if err != nil {
 		userName = user
 	}
-	rtm.SendMessage(rtm.NewOutgoingMessage(fmt.Sprintf("カード番号%sを%sさんが借りました。", keyNum, userName), b.channelID))
+	return fmt.Sprintf("%s(カード番号%s)を%sさんが借りました。", card.Label, keyNum, userName)
 }
 
-// returnKey は指定されたキーを返却する処理を行います。
-func (b *Bot) returnKey(keyNum, user string, rtm *slack.RTM) {
+// doReturn は指定されたキーを返却する処理を行い、結果メッセージを返します。
+func (b *Bot) doReturn(keyNum, user string) string {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
+	if _, ok := b.cardInventory().Card(keyNum); !ok {
+		return fmt.Sprintf("カード番号が無効です: %s", keyNum)
+	}
+
 	status, exists := b.keyStatuses[keyNum]
 	if !exists {
-		rtm.SendMessage(rtm.NewOutgoingMessage(fmt.Sprintf("カード番号%sは現在貸し出されていません。", keyNum), b.channelID))
-		return
+		return fmt.Sprintf("カード番号%sは現在貸し出されていません。", keyNum)
 	}
 
 	if status.Borrower != user {
@@ -224,22 +382,74 @@ func (b *Bot) returnKey(keyNum, user string, rtm *slack.RTM) {
 		if err != nil {
 			borrowerName = status.Borrower
 		}
-		rtm.SendMessage(rtm.NewOutgoingMessage(fmt.Sprintf("カード番号%sは%sさんが借りています。あなたは借りていません。", keyNum, borrowerName), b.channelID))
-		return
+		return fmt.Sprintf("カード番号%sは%sさんが借りています。あなたは借りていません。", keyNum, borrowerName)
+	}
+
+	returnedAt := time.Now()
+	if err := b.store.ReturnKey(keyNum, user, returnedAt); err != nil {
+		eventLogger.Error("failed to persist return", "key_num", keyNum, "user_id", user, "action", "return", "error", err)
+		return "返却状況の保存に失敗しました。もう一度お試しください。"
 	}
 
 	delete(b.keyStatuses, keyNum)
-	rtm.SendMessage(rtm.NewOutgoingMessage(fmt.Sprintf("カード番号%sが返却されました。", keyNum), b.channelID))
+
+	metrics.ReturnsTotal.Inc()
+	metrics.LoanedCards.WithLabelValues(keyNum).Set(0)
+	metrics.LoanDurationSeconds.Observe(returnedAt.Sub(status.BorrowedAt).Seconds())
+	eventLogger.Info("key returned", "key_num", keyNum, "user_id", user, "action", "return")
+
+	return fmt.Sprintf("カード番号%sが返却されました。", keyNum)
 }
 
-// reportStatus は現在のキーの貸し出し状況を報告します。
-func (b *Bot) reportStatus(rtm *slack.RTM, channelID string) {
+// doHistory は指定されたカードの直近の貸し出し履歴をまとめたメッセージを返します。
+func (b *Bot) doHistory(keyNum string) string {
+	const recentLoanLimit = 10
+
+	entries, err := b.store.History(keyNum, recentLoanLimit)
+	if err != nil {
+		log.Printf("Failed to load history for key %s: %v\n", keyNum, err)
+		return "履歴の取得に失敗しました。"
+	}
+
+	if len(entries) == 0 {
+		return fmt.Sprintf("カード番号%sの履歴はありません。", keyNum)
+	}
+
+	var report strings.Builder
+	report.WriteString(fmt.Sprintf("カード番号%sの履歴 (直近%d件):\n", keyNum, len(entries)))
+	for _, entry := range entries {
+		borrowerName, err := b.getUserName(entry.Borrower)
+		if err != nil {
+			borrowerName = entry.Borrower
+		}
+
+		if entry.ReturnedAt.IsZero() {
+			report.WriteString(fmt.Sprintf("%s 〜 貸し出し中: %sさん\n",
+				entry.BorrowedAt.Format("2006-01-02 15:04"), borrowerName))
+			continue
+		}
+
+		returnerName, err := b.getUserName(entry.ReturnedBy)
+		if err != nil {
+			returnerName = entry.ReturnedBy
+		}
+		report.WriteString(fmt.Sprintf("%s 〜 %s: %sさんが借り、%sさんが返却\n",
+			entry.BorrowedAt.Format("2006-01-02 15:04"), entry.ReturnedAt.Format("2006-01-02 15:04"),
+			borrowerName, returnerName))
+	}
+
+	return report.String()
+}
+
+// doStatus は現在のキーの貸し出し状況をまとめたメッセージを返します。
+func (b *Bot) doStatus() string {
+	inv := b.cardInventory()
+
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
 	if len(b.keyStatuses) == 0 {
-		rtm.SendMessage(rtm.NewOutgoingMessage("現在、貸し出されているマスターキーはありません。", channelID))
-		return
+		return "現在、貸し出されているマスターキーはありません。"
 	}
 
 	var report strings.Builder
@@ -249,59 +459,64 @@ func (b *Bot) reportStatus(rtm *slack.RTM, channelID string) {
 		if err != nil {
 			userName = status.Borrower
 		}
-		report.WriteString(fmt.Sprintf("カード番号%s: %sさんが借りています。借りた日: %s\n",
-			status.KeyNumber, userName, status.BorrowedAt.Format("2006-01-02 15:04")))
+		report.WriteString(fmt.Sprintf("%s(カード番号%s): %sさんが借りています。借りた日: %s\n",
+			cardLabel(inv, status.KeyNumber), status.KeyNumber, userName, status.BorrowedAt.Format("2006-01-02 15:04")))
 	}
 
-	rtm.SendMessage(rtm.NewOutgoingMessage(report.String(), channelID))
+	return report.String()
 }
 
-// overdueChecker は定期的に2日以上返却されていないキーをチェックします。
-func (b *Bot) overdueChecker() {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
+// buildStatusBlocks はカードごとの貸し出し状況を、借りる/返すボタン付きのBlock Kitメッセージとして組み立てます。
+func (b *Bot) buildStatusBlocks() []slack.Block {
+	inv := b.cardInventory()
 
-	for range ticker.C {
-		b.checkOverdue()
-	}
-}
-
-// checkOverdue は2日以上返却されていないキーを検出し、通知を投稿します。
-func (b *Bot) checkOverdue() {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	now := time.Now()
-	var overdueKeys []string
-
-	for keyNum, status := range b.keyStatuses {
-		if now.Sub(status.BorrowedAt) > 48*time.Hour {
-			overdueKeys = append(overdueKeys, keyNum)
-		}
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "マスターキーの貸し出し状況", false, false)),
 	}
 
-	if len(overdueKeys) > 0 {
-		message := "以下のマスターキーが2日以上経過しても返却されていません:\n"
-		for _, key := range overdueKeys {
-			status := b.keyStatuses[key]
+	for _, card := range inv.Cards {
+		status, borrowed := b.keyStatuses[card.ID]
+
+		var desc, buttonText, actionID string
+		style := slack.StylePrimary
+		if borrowed {
 			userName, err := b.getUserName(status.Borrower)
 			if err != nil {
 				userName = status.Borrower
 			}
-			message += fmt.Sprintf("カード番号%s: %sさんが借りています。借りた日: %s\n",
-				key, userName, status.BorrowedAt.Format("2006-01-02 15:04"))
+			desc = fmt.Sprintf("*%s*(カード番号%s)\n%sさんが貸出中。借りた日: %s",
+				card.Label, card.ID, userName, status.BorrowedAt.Format("2006-01-02 15:04"))
+			buttonText, actionID, style = "返す", "return_key", slack.StyleDanger
+		} else {
+			desc = fmt.Sprintf("*%s*(カード番号%s)\n貸出可能", card.Label, card.ID)
+			buttonText, actionID = "借りる", "borrow_key"
 		}
-		b.api.PostMessage(b.channelID, slack.MsgOptionText(message, false))
+
+		button := slack.NewButtonBlockElement(actionID, card.ID,
+			slack.NewTextBlockObject(slack.PlainTextType, buttonText, false, false)).WithStyle(style)
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, desc, false, false),
+			nil,
+			slack.NewAccessory(button),
+		))
+	}
+
+	return blocks
+}
+
+// postStatusBlocks はチャンネルに貸し出し状況のBlock Kitメッセージを投稿します。
+func (b *Bot) postStatusBlocks(channelID string) {
+	if _, _, err := b.slack.Client().PostMessage(channelID, slack.MsgOptionBlocks(b.buildStatusBlocks()...)); err != nil {
+		log.Printf("Failed to post status blocks to %s: %v\n", channelID, err)
 	}
 }
 
 // getUserName はユーザーIDからユーザー名を取得します。
 func (b *Bot) getUserName(userID string) (string, error) {
-	user, err := b.api.GetUserInfo(userID)
-	if err != nil {
-		return "", err
-	}
-	return user.RealName, nil
+	return b.backend.LookupUser(userID)
 }
 
 func main() {
@@ -311,34 +526,112 @@ func main() {
 		log.Println("Warning: .envファイルの読み込みに失敗しました。環境変数が設定されていることを確認してください。")
 	}
 
-	slackToken := os.Getenv("SLACK_BOT_TOKEN")
-	if slackToken == "" {
-		log.Fatal("環境変数 SLACK_BOT_TOKEN を設定してください")
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "master-card-manager.db" // デフォルト値
 	}
 
-	api := slack.New(slackToken)
-	listChannels(api) // デバッグ用: アクセス可能なチャンネル一覧を出力
+	cardsConfig := os.Getenv("CARDS_CONFIG")
+	if cardsConfig == "" {
+		cardsConfig = "cards.yaml" // デフォルト値
+	}
 
-	channelName := os.Getenv("CHANNEL_NAME")
-	if channelName == "" {
-		channelName = "general" // デフォルト値
+	chatBackend := os.Getenv("CHAT_BACKEND")
+	if chatBackend == "" {
+		chatBackend = "slack" // デフォルト値
 	}
 
-	bot, err := NewBot(slackToken, channelName)
-	if err != nil {
-		log.Fatalf("ボットの初期化に失敗しました: %v", err)
+	var (
+		backend       chat.Backend
+		slackBackend  *slackchat.Backend
+		channelID     string
+		botUserID     string
+		signingSecret string
+	)
+
+	switch chatBackend {
+	case "slack":
+		slackToken := os.Getenv("SLACK_BOT_TOKEN")
+		if slackToken == "" {
+			log.Fatal("環境変数 SLACK_BOT_TOKEN を設定してください")
+		}
+
+		appToken := os.Getenv("SLACK_APP_TOKEN")
+		if appToken == "" {
+			log.Fatal("環境変数 SLACK_APP_TOKEN を設定してください(Socket Mode用のapp-levelトークン)")
+		}
+
+		signingSecret = os.Getenv("SLACK_SIGNING_SECRET")
+		if signingSecret == "" {
+			log.Fatal("環境変数 SLACK_SIGNING_SECRET を設定してください(block_actions検証用)")
+		}
+
+		channelName := os.Getenv("CHANNEL_NAME")
+		if channelName == "" {
+			channelName = "general" // デフォルト値
+		}
+
+		sb, cid, uid, err := newSlackBackend(slackToken, appToken, channelName)
+		if err != nil {
+			log.Fatalf("Slack Backendの初期化に失敗しました: %v", err)
+		}
+		backend, slackBackend, channelID, botUserID = sb, sb, cid, uid
+		fmt.Printf("Bot connected as Slack user ID: %s\n", botUserID)
+
+	case "discord":
+		discordToken := os.Getenv("DISCORD_BOT_TOKEN")
+		if discordToken == "" {
+			log.Fatal("環境変数 DISCORD_BOT_TOKEN を設定してください")
+		}
+
+		channelID = os.Getenv("DISCORD_CHANNEL_ID")
+		if channelID == "" {
+			log.Fatal("環境変数 DISCORD_CHANNEL_ID を設定してください")
+		}
+
+		db, uid, err := newDiscordBackend(discordToken, channelID)
+		if err != nil {
+			log.Fatalf("Discord Backendの初期化に失敗しました: %v", err)
+		}
+		backend, botUserID = db, uid
+		fmt.Printf("Bot connected as Discord user ID: %s\n", botUserID)
+
+	default:
+		log.Fatalf("不明なCHAT_BACKENDです: %s", chatBackend)
 	}
-	// トークンの検証
-	authTest, err := api.AuthTest()
+
+	bot, err := NewBot(backend, slackBackend, channelID, botUserID, dbPath, cardsConfig, signingSecret)
 	if err != nil {
-		log.Fatalf("AuthTest failed: %v", err)
+		log.Fatalf("ボットの初期化に失敗しました: %v", err)
 	}
+	defer bot.store.Close()
 
-	fmt.Printf("Bot connected as: %s (ID: %s)\n", authTest.User, authTest.UserID)
+	// block_actions/view_submissionのインタラクションはSlack固有の機能
+	if slackBackend != nil {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000" // デフォルト値
+		}
+		go bot.runInteractionsServer(port)
+	}
 
-	// ボットが参加しているチャンネルをリストアップ
-	listChannels(api)
+	metricsPort := os.Getenv("METRICS_PORT")
+	if metricsPort == "" {
+		metricsPort = "2112" // デフォルト値
+	}
+	go runMetricsServer(metricsPort)
 
-	log.Println("ボットを起動します...")
 	bot.Run()
 }
+
+// runMetricsServer はPrometheusが/metricsをスクレイプするためのHTTPサーバーを起動します。
+func runMetricsServer(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	addr := fmt.Sprintf(":%s", port)
+	log.Printf("Listening for Prometheus scrapes on %s\n", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("メトリクスサーバーの起動に失敗しました: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Package metrics はボットの運用状態をPrometheus形式で公開するためのメトリクスを定義します。
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "master_card_manager"
+
+var (
+	// BorrowsTotal はカードの貸し出しが成功した回数です。
+	BorrowsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "borrows_total",
+		Help:      "Total number of successful key borrows.",
+	})
+
+	// ReturnsTotal はカードの返却が成功した回数です。
+	ReturnsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "returns_total",
+		Help:      "Total number of successful key returns.",
+	})
+
+	// OverdueNotificationsTotal は延滞リマインド(DM/@here)を送信した回数です。
+	OverdueNotificationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "overdue_notifications_total",
+		Help:      "Total number of overdue reminder notifications sent.",
+	})
+
+	// ReconnectsTotal はチャットバックエンドが接続・再接続した回数です(初回の接続も含みます)。
+	ReconnectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconnects_total",
+		Help:      "Total number of chat backend connections and reconnects (including the initial connection), labeled by backend.",
+	}, []string{"backend"})
+
+	// APIErrorsTotal はチャットサービスのAPI呼び出しが失敗した回数です。
+	APIErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_errors_total",
+		Help:      "Total number of chat backend API errors, labeled by backend.",
+	}, []string{"backend"})
+
+	// LoanedCards は現在貸し出し中かどうかをカード番号ごとに表すゲージです(1=貸出中, 0=返却済み)。
+	LoanedCards = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "loaned_cards",
+		Help:      "Whether a given card is currently on loan (1) or not (0).",
+	}, []string{"key_number"})
+
+	// LoanDurationSeconds は貸し出しから返却までの所要時間の分布です。
+	LoanDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "loan_duration_seconds",
+		Help:      "Duration a card stayed on loan, in seconds.",
+		Buckets:   []float64{300, 900, 1800, 3600, 4 * 3600, 8 * 3600, 24 * 3600, 2 * 24 * 3600, 7 * 24 * 3600},
+	})
+)
+
+// Handler は/metricsエンドポイント用のhttp.Handlerを返します。
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
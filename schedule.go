@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/slack-go/slack"
+
+	"hihumikan/master-card-manager/inventory"
+	"hihumikan/master-card-manager/metrics"
+)
+
+// loanDigestItem はダイジェスト系テンプレート(target: channel)に渡す1件分の貸し出し情報です。
+type loanDigestItem struct {
+	KeyNumber    string
+	Label        string
+	BorrowerName string
+	BorrowedAt   string
+}
+
+// loanReminderData は個別リマインド系テンプレート(target: dm/mention)に渡す貸し出し情報です。
+type loanReminderData struct {
+	KeyNumber    string
+	Label        string
+	BorrowerName string
+	BorrowedAt   string
+	HoursElapsed int
+}
+
+// startScheduler はカード設定のschedulesをcron.Cronに登録して起動します。
+// cron式はカード設定のtimezone(未指定ならAsia/Tokyo)に基づいて評価されます。
+func (b *Bot) startScheduler() {
+	inv := b.cardInventory()
+
+	loc, err := inv.Location()
+	if err != nil {
+		log.Printf("Failed to load schedule timezone, falling back to UTC: %v\n", err)
+		loc = time.UTC
+	}
+
+	b.cron = cron.New(cron.WithLocation(loc))
+	b.registerScheduleRules(inv.Schedules)
+	b.cron.Start()
+}
+
+// reloadScheduler は既存のcronジョブを破棄し、現在の在庫設定のschedulesで登録し直します。
+func (b *Bot) reloadScheduler() {
+	if b.cron != nil {
+		b.cron.Stop()
+	}
+	b.startScheduler()
+}
+
+func (b *Bot) registerScheduleRules(rules []inventory.ScheduleRule) {
+	for _, rule := range rules {
+		rule := rule
+		if _, err := b.cron.AddFunc(rule.Cron, func() { b.runScheduleRule(rule) }); err != nil {
+			log.Printf("Failed to schedule rule %s: %v\n", rule.Name, err)
+		}
+	}
+}
+
+// runScheduleRule はルールのtargetに応じてダイジェストまたは個別リマインドを実行します。
+func (b *Bot) runScheduleRule(rule inventory.ScheduleRule) {
+	switch rule.Target {
+	case inventory.ScheduleTargetChannel:
+		b.runDigestRule(rule)
+	case inventory.ScheduleTargetDM:
+		b.runPerLoanRule(rule, false)
+	case inventory.ScheduleTargetMention:
+		b.runPerLoanRule(rule, true)
+	default:
+		log.Printf("Unknown schedule target %q for rule %q\n", rule.Target, rule.Name)
+	}
+}
+
+// runDigestRule は現在の全貸し出しをまとめてチャンネルに投稿します。
+func (b *Bot) runDigestRule(rule inventory.ScheduleRule) {
+	inv := b.cardInventory()
+
+	b.mutex.Lock()
+	items := make([]loanDigestItem, 0, len(b.keyStatuses))
+	for _, status := range b.keyStatuses {
+		items = append(items, loanDigestItem{
+			KeyNumber:    status.KeyNumber,
+			Label:        cardLabel(inv, status.KeyNumber),
+			BorrowerName: b.borrowerName(status.Borrower),
+			BorrowedAt:   status.BorrowedAt.Format("2006-01-02 15:04"),
+		})
+	}
+	b.mutex.Unlock()
+
+	text, err := renderTemplate(rule.Name, rule.Template, struct{ Loans []loanDigestItem }{Loans: items})
+	if err != nil {
+		log.Printf("Failed to render schedule rule %s: %v\n", rule.Name, err)
+		return
+	}
+
+	b.postMessage(b.channelID, text)
+}
+
+// runPerLoanRule はrule.AfterHoursを超えて貸し出されているカードごとにリマインドを送ります。
+// AfterHoursが未指定の場合は、カードごとのmax_loan_hours(またはグローバルなデフォルト)を
+// しきい値として使います。mentionがtrueの場合はチャンネルへ、falseの場合は借り主へのDMとして送信します。
+func (b *Bot) runPerLoanRule(rule inventory.ScheduleRule, mention bool) {
+	inv := b.cardInventory()
+	now := time.Now()
+
+	b.mutex.Lock()
+	var targets []loanReminderData
+	var borrowers []string
+	for _, status := range b.keyStatuses {
+		threshold := reminderThreshold(rule, inv, status.KeyNumber)
+		if !isOverdue(*status, threshold, now) {
+			continue
+		}
+
+		hoursElapsed := int(now.Sub(status.BorrowedAt).Hours())
+		targets = append(targets, loanReminderData{
+			KeyNumber:    status.KeyNumber,
+			Label:        cardLabel(inv, status.KeyNumber),
+			BorrowerName: b.borrowerName(status.Borrower),
+			BorrowedAt:   status.BorrowedAt.Format("2006-01-02 15:04"),
+			HoursElapsed: hoursElapsed,
+		})
+		borrowers = append(borrowers, status.Borrower)
+	}
+	b.mutex.Unlock()
+
+	for i, data := range targets {
+		text, err := renderTemplate(rule.Name, rule.Template, data)
+		if err != nil {
+			log.Printf("Failed to render schedule rule %s: %v\n", rule.Name, err)
+			continue
+		}
+
+		// DM送信(target: dm)はSlack固有の機能なので、それ以外のBackendではチャンネルへの通知にフォールバックする
+		if mention || b.slack == nil {
+			if err := b.postMessage(b.channelID, text); err != nil {
+				eventLogger.Error("failed to send overdue notification", "key_num", data.KeyNumber, "user_id", borrowers[i], "action", "overdue_notification", "error", err)
+				continue
+			}
+			metrics.OverdueNotificationsTotal.Inc()
+			eventLogger.Info("overdue notification sent", "key_num", data.KeyNumber, "user_id", borrowers[i], "action", "overdue_notification")
+			continue
+		}
+
+		if err := b.sendDM(borrowers[i], text); err != nil {
+			eventLogger.Error("failed to send overdue DM", "key_num", data.KeyNumber, "user_id", borrowers[i], "action", "overdue_notification", "error", err)
+			continue
+		}
+		metrics.OverdueNotificationsTotal.Inc()
+		eventLogger.Info("overdue notification sent", "key_num", data.KeyNumber, "user_id", borrowers[i], "action", "overdue_notification")
+	}
+}
+
+// sendDM はuserIDとのダイレクトメッセージチャンネルを開いてtextを送ります。
+func (b *Bot) sendDM(userID, text string) error {
+	channel, _, _, err := b.slack.Client().OpenConversation(&slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		return err
+	}
+	_, _, err = b.slack.Client().PostMessage(channel.ID, slack.MsgOptionText(text, false))
+	return err
+}
+
+// borrowerName はuserIDの表示名を取得し、失敗した場合はuserIDをそのまま返します。
+func (b *Bot) borrowerName(userID string) string {
+	name, err := b.getUserName(userID)
+	if err != nil {
+		return userID
+	}
+	return name
+}
+
+// reminderThreshold はruleの延滞リマインドを送るまでの経過時間のしきい値を返します。
+// rule.AfterHoursが指定されていればそれを、未指定ならカードごとのmax_loan_hours
+// (またはグローバルなデフォルト)を使います。
+func reminderThreshold(rule inventory.ScheduleRule, inv *inventory.Inventory, keyNum string) time.Duration {
+	if rule.AfterHours > 0 {
+		return time.Duration(rule.AfterHours) * time.Hour
+	}
+	return inv.MaxLoanDuration(keyNum, defaultMaxLoanDuration)
+}
+
+// isOverdue はstatusがthresholdを超えて貸し出されているか、または返却予定日を
+// 過ぎているかを返します。
+func isOverdue(status KeyStatus, threshold time.Duration, now time.Time) bool {
+	pastExpectedReturn := !status.ExpectedReturn.IsZero() && now.After(status.ExpectedReturn)
+	return now.Sub(status.BorrowedAt) >= threshold || pastExpectedReturn
+}
+
+// cardLabel はinvからkeyNumに対応するカードのラベルを取得します。見つからない場合はkeyNumをそのまま返します。
+func cardLabel(inv *inventory.Inventory, keyNum string) string {
+	card, ok := inv.Card(keyNum)
+	if !ok {
+		return keyNum
+	}
+	return card.Label
+}
+
+// renderTemplate はルールのテンプレート文字列をdataで展開します。
+func renderTemplate(ruleName, templateText string, data interface{}) (string, error) {
+	tmpl, err := template.New(ruleName).Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("テンプレートの解析に失敗しました: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("テンプレートの実行に失敗しました: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// formatScheduleList はアクティブなスケジュールルールの一覧をまとめたメッセージを返します。
+func formatScheduleList(rules []inventory.ScheduleRule) string {
+	if len(rules) == 0 {
+		return "現在、有効なスケジュールルールはありません。"
+	}
+
+	var report strings.Builder
+	report.WriteString("現在のスケジュールルール:\n")
+	for _, rule := range rules {
+		report.WriteString(fmt.Sprintf("- %s (cron: %s, target: %s)\n", rule.Name, rule.Cron, rule.Target))
+	}
+	return report.String()
+}
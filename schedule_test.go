@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"hihumikan/master-card-manager/inventory"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := loanReminderData{KeyNumber: "13", Label: "マスターキー13", HoursElapsed: 30}
+
+	text, err := renderTemplate("test_rule", "{{.Label}}(カード番号{{.KeyNumber}})は{{.HoursElapsed}}時間経過", data)
+	if err != nil {
+		t.Fatalf("renderTemplate failed: %v", err)
+	}
+	want := "マスターキー13(カード番号13)は30時間経過"
+	if text != want {
+		t.Errorf("renderTemplate = %q, want %q", text, want)
+	}
+}
+
+func TestRenderTemplateInvalidSyntax(t *testing.T) {
+	if _, err := renderTemplate("bad_rule", "{{.Unclosed", nil); err == nil {
+		t.Fatal("expected an error for invalid template syntax, got nil")
+	}
+}
+
+func TestFormatScheduleListEmpty(t *testing.T) {
+	got := formatScheduleList(nil)
+	if !strings.Contains(got, "ありません") {
+		t.Errorf("formatScheduleList(nil) = %q, want a message indicating no rules", got)
+	}
+}
+
+func TestFormatScheduleListIncludesRuleDetails(t *testing.T) {
+	rules := []inventory.ScheduleRule{
+		{Name: "daily_digest", Cron: "0 9 * * *", Target: inventory.ScheduleTargetChannel},
+	}
+
+	got := formatScheduleList(rules)
+	for _, want := range []string{"daily_digest", "0 9 * * *", "channel"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatScheduleList result %q does not contain %q", got, want)
+		}
+	}
+}
+
+func TestCardLabelFallsBackToKeyNumber(t *testing.T) {
+	inv := &inventory.Inventory{Cards: []inventory.Card{{ID: "13", Label: "マスターキー13"}}}
+
+	if got := cardLabel(inv, "13"); got != "マスターキー13" {
+		t.Errorf("cardLabel(13) = %q, want %q", got, "マスターキー13")
+	}
+	if got := cardLabel(inv, "99"); got != "99" {
+		t.Errorf("cardLabel(99) = %q, want %q (fallback to key number)", got, "99")
+	}
+}
+
+func TestReminderThresholdUsesRuleAfterHoursWhenSet(t *testing.T) {
+	inv := &inventory.Inventory{Cards: []inventory.Card{{ID: "13", Label: "マスターキー13", MaxLoanHours: 48}}}
+	rule := inventory.ScheduleRule{AfterHours: 24}
+
+	if got := reminderThreshold(rule, inv, "13"); got != 24*time.Hour {
+		t.Errorf("reminderThreshold = %v, want %v (rule.AfterHours)", got, 24*time.Hour)
+	}
+}
+
+func TestReminderThresholdFallsBackToCardMaxLoanDuration(t *testing.T) {
+	inv := &inventory.Inventory{Cards: []inventory.Card{{ID: "13", Label: "マスターキー13", MaxLoanHours: 12}}}
+	rule := inventory.ScheduleRule{}
+
+	if got := reminderThreshold(rule, inv, "13"); got != 12*time.Hour {
+		t.Errorf("reminderThreshold = %v, want %v (card max_loan_hours)", got, 12*time.Hour)
+	}
+}
+
+func TestReminderThresholdFallsBackToDefaultForUnknownCard(t *testing.T) {
+	inv := &inventory.Inventory{Cards: []inventory.Card{{ID: "13", Label: "マスターキー13"}}}
+	rule := inventory.ScheduleRule{}
+
+	if got := reminderThreshold(rule, inv, "99"); got != defaultMaxLoanDuration {
+		t.Errorf("reminderThreshold = %v, want %v (default)", got, defaultMaxLoanDuration)
+	}
+}
+
+func TestIsOverdueBelowThreshold(t *testing.T) {
+	now := time.Now()
+	status := KeyStatus{KeyNumber: "13", BorrowedAt: now.Add(-time.Hour)}
+
+	if isOverdue(status, 24*time.Hour, now) {
+		t.Error("expected isOverdue to be false when borrowed time is below the threshold")
+	}
+}
+
+func TestIsOverduePastThreshold(t *testing.T) {
+	now := time.Now()
+	status := KeyStatus{KeyNumber: "13", BorrowedAt: now.Add(-25 * time.Hour)}
+
+	if !isOverdue(status, 24*time.Hour, now) {
+		t.Error("expected isOverdue to be true when borrowed time exceeds the threshold")
+	}
+}
+
+func TestIsOverduePastExpectedReturnEvenBelowThreshold(t *testing.T) {
+	now := time.Now()
+	status := KeyStatus{
+		KeyNumber:      "13",
+		BorrowedAt:     now.Add(-time.Hour),
+		ExpectedReturn: now.Add(-time.Minute),
+	}
+
+	if !isOverdue(status, 24*time.Hour, now) {
+		t.Error("expected isOverdue to be true once the expected return time has passed, regardless of the threshold")
+	}
+}
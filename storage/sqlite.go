@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteStore は Store の SQLite 実装です。
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore は path のSQLiteファイルを開き、必要なテーブルを作成します。
+func NewSQLiteStore(path string) (Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("DBを開けませんでした: %v", err)
+	}
+	// go-sqlite3 は複数コネクションからの同時書き込みに弱いため1本に制限する。
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("マイグレーションに失敗しました: %v", err)
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`
+CREATE TABLE IF NOT EXISTS key_status (
+	key_number      TEXT PRIMARY KEY,
+	borrower        TEXT NOT NULL,
+	borrowed_at     DATETIME NOT NULL,
+	expected_return DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS loan_history (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	key_number  TEXT NOT NULL,
+	borrower    TEXT NOT NULL,
+	borrowed_at DATETIME NOT NULL,
+	returned_at DATETIME,
+	returned_by TEXT
+);
+`)
+	return err
+}
+
+func (s *sqliteStore) ActiveLoans() (map[string]*Loan, error) {
+	rows, err := s.db.Query(`SELECT key_number, borrower, borrowed_at, expected_return FROM key_status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loans := make(map[string]*Loan)
+	for rows.Next() {
+		var (
+			loan           Loan
+			expectedReturn sql.NullTime
+		)
+		if err := rows.Scan(&loan.KeyNumber, &loan.Borrower, &loan.BorrowedAt, &expectedReturn); err != nil {
+			return nil, err
+		}
+		loan.ExpectedReturn = expectedReturn.Time
+		loans[loan.KeyNumber] = &loan
+	}
+	return loans, rows.Err()
+}
+
+func (s *sqliteStore) BorrowKey(keyNumber, borrower string, borrowedAt, expectedReturn time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var expectedReturnArg interface{}
+	if !expectedReturn.IsZero() {
+		expectedReturnArg = expectedReturn
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO key_status (key_number, borrower, borrowed_at, expected_return) VALUES (?, ?, ?, ?)`,
+		keyNumber, borrower, borrowedAt, expectedReturnArg,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO loan_history (key_number, borrower, borrowed_at) VALUES (?, ?, ?)`,
+		keyNumber, borrower, borrowedAt,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStore) ReturnKey(keyNumber, returnedBy string, returnedAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM key_status WHERE key_number = ?`, keyNumber); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE loan_history SET returned_at = ?, returned_by = ?
+		 WHERE id = (
+			SELECT id FROM loan_history
+			WHERE key_number = ? AND returned_at IS NULL
+			ORDER BY borrowed_at DESC LIMIT 1
+		 )`,
+		returnedAt, returnedBy, keyNumber,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStore) History(keyNumber string, limit int) ([]HistoryEntry, error) {
+	rows, err := s.db.Query(
+		`SELECT key_number, borrower, borrowed_at, returned_at, returned_by
+		 FROM loan_history WHERE key_number = ?
+		 ORDER BY borrowed_at DESC LIMIT ?`,
+		keyNumber, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryEntry
+	for rows.Next() {
+		var (
+			entry      HistoryEntry
+			returnedAt sql.NullTime
+			returnedBy sql.NullString
+		)
+		if err := rows.Scan(&entry.KeyNumber, &entry.Borrower, &entry.BorrowedAt, &returnedAt, &returnedBy); err != nil {
+			return nil, err
+		}
+		entry.ReturnedAt = returnedAt.Time
+		entry.ReturnedBy = returnedBy.String
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) Store {
+	t.Helper()
+
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestActiveLoansEmptyByDefault(t *testing.T) {
+	store := newTestStore(t)
+
+	loans, err := store.ActiveLoans()
+	if err != nil {
+		t.Fatalf("ActiveLoans failed: %v", err)
+	}
+	if len(loans) != 0 {
+		t.Fatalf("expected no active loans, got %d", len(loans))
+	}
+}
+
+func TestBorrowKeyThenActiveLoans(t *testing.T) {
+	store := newTestStore(t)
+
+	borrowedAt := time.Now().Truncate(time.Second)
+	expectedReturn := borrowedAt.Add(24 * time.Hour)
+	if err := store.BorrowKey("13", "U001", borrowedAt, expectedReturn); err != nil {
+		t.Fatalf("BorrowKey failed: %v", err)
+	}
+
+	loans, err := store.ActiveLoans()
+	if err != nil {
+		t.Fatalf("ActiveLoans failed: %v", err)
+	}
+	loan, ok := loans["13"]
+	if !ok {
+		t.Fatalf("expected an active loan for key 13, got %v", loans)
+	}
+	if loan.Borrower != "U001" {
+		t.Errorf("Borrower = %q, want %q", loan.Borrower, "U001")
+	}
+	if !loan.BorrowedAt.Equal(borrowedAt) {
+		t.Errorf("BorrowedAt = %v, want %v", loan.BorrowedAt, borrowedAt)
+	}
+	if !loan.ExpectedReturn.Equal(expectedReturn) {
+		t.Errorf("ExpectedReturn = %v, want %v", loan.ExpectedReturn, expectedReturn)
+	}
+}
+
+func TestBorrowKeyWithoutExpectedReturn(t *testing.T) {
+	store := newTestStore(t)
+
+	borrowedAt := time.Now().Truncate(time.Second)
+	if err := store.BorrowKey("14", "U002", borrowedAt, time.Time{}); err != nil {
+		t.Fatalf("BorrowKey failed: %v", err)
+	}
+
+	loans, err := store.ActiveLoans()
+	if err != nil {
+		t.Fatalf("ActiveLoans failed: %v", err)
+	}
+	if !loans["14"].ExpectedReturn.IsZero() {
+		t.Errorf("ExpectedReturn = %v, want zero value", loans["14"].ExpectedReturn)
+	}
+}
+
+func TestReturnKeyRemovesActiveLoanAndRecordsHistory(t *testing.T) {
+	store := newTestStore(t)
+
+	borrowedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := store.BorrowKey("13", "U001", borrowedAt, time.Time{}); err != nil {
+		t.Fatalf("BorrowKey failed: %v", err)
+	}
+
+	returnedAt := time.Now().Truncate(time.Second)
+	if err := store.ReturnKey("13", "U001", returnedAt); err != nil {
+		t.Fatalf("ReturnKey failed: %v", err)
+	}
+
+	loans, err := store.ActiveLoans()
+	if err != nil {
+		t.Fatalf("ActiveLoans failed: %v", err)
+	}
+	if _, ok := loans["13"]; ok {
+		t.Fatalf("expected key 13 to no longer be on loan, got %v", loans)
+	}
+
+	history, err := store.History("13", 10)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("expected 1 history entry, got %d", len(history))
+	}
+	entry := history[0]
+	if entry.Borrower != "U001" {
+		t.Errorf("Borrower = %q, want %q", entry.Borrower, "U001")
+	}
+	if entry.ReturnedBy != "U001" {
+		t.Errorf("ReturnedBy = %q, want %q", entry.ReturnedBy, "U001")
+	}
+	if !entry.ReturnedAt.Equal(returnedAt) {
+		t.Errorf("ReturnedAt = %v, want %v", entry.ReturnedAt, returnedAt)
+	}
+}
+
+func TestHistoryKeepsOpenLoanEntryAcrossMultipleBorrows(t *testing.T) {
+	store := newTestStore(t)
+
+	firstBorrowedAt := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	if err := store.BorrowKey("13", "U001", firstBorrowedAt, time.Time{}); err != nil {
+		t.Fatalf("BorrowKey failed: %v", err)
+	}
+	if err := store.ReturnKey("13", "U001", firstBorrowedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("ReturnKey failed: %v", err)
+	}
+
+	secondBorrowedAt := time.Now().Truncate(time.Second)
+	if err := store.BorrowKey("13", "U002", secondBorrowedAt, time.Time{}); err != nil {
+		t.Fatalf("BorrowKey (second) failed: %v", err)
+	}
+
+	history, err := store.History("13", 10)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(history))
+	}
+
+	// History は borrowed_at の降順で返るため、先頭が直近(未返却)の貸し出しになる。
+	if !history[0].ReturnedAt.IsZero() {
+		t.Errorf("expected the most recent loan to still be open, got ReturnedAt=%v", history[0].ReturnedAt)
+	}
+	if history[0].Borrower != "U002" {
+		t.Errorf("Borrower = %q, want %q", history[0].Borrower, "U002")
+	}
+	if history[1].ReturnedAt.IsZero() {
+		t.Errorf("expected the earlier loan to be returned")
+	}
+}
+
+func TestHistoryRespectsLimit(t *testing.T) {
+	store := newTestStore(t)
+
+	for i := 0; i < 5; i++ {
+		borrowedAt := time.Now().Add(time.Duration(-i) * time.Hour).Truncate(time.Second)
+		if err := store.BorrowKey("13", "U001", borrowedAt, time.Time{}); err != nil {
+			t.Fatalf("BorrowKey failed: %v", err)
+		}
+		if err := store.ReturnKey("13", "U001", borrowedAt.Add(time.Minute)); err != nil {
+			t.Fatalf("ReturnKey failed: %v", err)
+		}
+	}
+
+	history, err := store.History("13", 3)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected History to be limited to 3 entries, got %d", len(history))
+	}
+}
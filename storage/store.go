@@ -0,0 +1,42 @@
+package storage
+
+import "time"
+
+// Loan は現在貸し出し中のカードの状態を表します。
+// ExpectedReturn はBlock Kitの貸し出しモーダルで返却予定日が指定された場合のみ設定され、
+// 未設定の場合はゼロ値になります。
+type Loan struct {
+	KeyNumber      string
+	Borrower       string
+	BorrowedAt     time.Time
+	ExpectedReturn time.Time
+}
+
+// HistoryEntry は loan_history テーブルの1レコードを表します。
+// ReturnedAt / ReturnedBy はまだ返却されていない場合は空になります。
+type HistoryEntry struct {
+	KeyNumber  string
+	Borrower   string
+	BorrowedAt time.Time
+	ReturnedAt time.Time
+	ReturnedBy string
+}
+
+// Store はキーの貸し出し状態と履歴を永続化するためのインターフェースです。
+type Store interface {
+	// ActiveLoans は現在貸し出し中の全カードを返します。
+	ActiveLoans() (map[string]*Loan, error)
+
+	// BorrowKey はカードの貸し出しを記録し、history にも追記します。
+	// expectedReturn はゼロ値の場合、返却予定日なしとして扱われます。
+	BorrowKey(keyNumber, borrower string, borrowedAt, expectedReturn time.Time) error
+
+	// ReturnKey はカードの返却を記録し、対応する history の行を更新します。
+	ReturnKey(keyNumber, returnedBy string, returnedAt time.Time) error
+
+	// History は指定されたカードの直近の貸し出し履歴を新しい順に返します。
+	History(keyNumber string, limit int) ([]HistoryEntry, error)
+
+	// Close は内部のDB接続を閉じます。
+	Close() error
+}